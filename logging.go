@@ -0,0 +1,119 @@
+package tea
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// LogToFile sets up default logging to a file. Call it before NewProgram so
+// that anything logged with the standard log package, or with
+// Program.Log/Logf once the returned file is also passed to WithLogger,
+// ends up in path instead of on the controlling TTY, which raw mode would
+// otherwise corrupt. If WithLogger is given a different writer, Run
+// redirects the standard log package to that writer instead while it's
+// running. The caller is responsible for closing the returned file, e.g.
+// with a deferred Close in main:
+//
+//	f, err := tea.LogToFile("debug.log", "debug")
+//	if err != nil {
+//		fmt.Println("fatal:", err)
+//		os.Exit(1)
+//	}
+//	defer f.Close()
+func LogToFile(path string, prefix string) (*os.File, error) {
+	return LogToFileWith(path, prefix, log.Default())
+}
+
+// LogToFileWith does the same thing as LogToFile, but lets you supply the
+// *log.Logger to configure instead of assuming the standard library's
+// default logger.
+func LogToFileWith(path string, prefix string, logger *log.Logger) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file for logging: %w", err)
+	}
+
+	logger.SetOutput(f)
+	if prefix != "" {
+		logger.SetPrefix(prefix + " ")
+	}
+
+	return f, nil
+}
+
+// asyncWriter buffers writes to an underlying io.Writer on a background
+// goroutine, so a burst of log lines produced synchronously (e.g. from
+// inside Update) can't block on a slow writer. Writes that arrive faster
+// than the underlying writer can drain them are dropped rather than
+// allowed to back up: a debug log must never be able to stall the event
+// loop that's producing it.
+type asyncWriter struct {
+	mu     sync.Mutex
+	closed bool
+	lines  chan []byte
+	done   chan struct{}
+}
+
+// newAsyncWriter starts the draining goroutine and returns a writer backed
+// by it. Close should be called once w is no longer needed. A nil w is
+// replaced with io.Discard, so a caller that forgets to check never crashes
+// the background drain goroutine.
+func newAsyncWriter(w io.Writer) *asyncWriter {
+	if w == nil {
+		w = io.Discard
+	}
+
+	aw := &asyncWriter{
+		lines: make(chan []byte, 256),
+		done:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(aw.done)
+		for line := range aw.lines {
+			_, _ = w.Write(line)
+		}
+	}()
+
+	return aw
+}
+
+// Write implements io.Writer. It never blocks: p is copied and queued for
+// the background goroutine, or dropped if the queue is full. Writes that
+// arrive after Close are dropped rather than sent, since aw.lines is closed
+// by then and a leaked command calling Program.Log past shutdown must not
+// panic the process.
+func (aw *asyncWriter) Write(p []byte) (int, error) {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	if aw.closed {
+		return len(p), nil
+	}
+
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	select {
+	case aw.lines <- buf:
+	default:
+	}
+
+	return len(p), nil
+}
+
+// Close stops accepting writes and waits for the background goroutine to
+// drain whatever was already queued. It's safe to call concurrently with
+// Write: both hold mu, so Write either completes before aw.lines is closed
+// or observes aw.closed and drops the write instead of sending on it.
+func (aw *asyncWriter) Close() {
+	aw.mu.Lock()
+	aw.closed = true
+	close(aw.lines)
+	aw.mu.Unlock()
+
+	<-aw.done
+}
@@ -0,0 +1,106 @@
+package tea
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+)
+
+// ProgramOption is used to set options when initializing a Program. Program
+// can accept a variable number of options.
+//
+// Example usage:
+//
+//	p := NewProgram(model, WithInput(someInput), WithOutput(someOutput))
+type ProgramOption func(*Program)
+
+// WithSubscriber configures the program to subscribe to fn once it starts.
+// fn is called with the program's context and should return a channel of
+// messages; every message sent on that channel is delivered to Update in
+// the same way as Program.Subscribe. fn's context is cancelled when the
+// program shuts down, which is the signal for fn to close its channel.
+//
+// This lets an adapter be wired in at construction time rather than reached
+// for after NewProgram returns:
+//
+//	p := NewProgram(model, WithSubscriber(partybus.Listen))
+func WithSubscriber(fn func(context.Context) <-chan Msg) ProgramOption {
+	return func(p *Program) {
+		p.subscribers = append(p.subscribers, fn)
+	}
+}
+
+// WithFilter supplies an event-loop middleware that's given every message
+// the event loop reads before the program's own handling of it: before the
+// internal switch that interprets quitMsg, batchMsg, sequenceMsg, execMsg
+// and the like, and before the message reaches Update. fn receives the
+// current model so it can make filtering decisions based on program state.
+// Messages produced during the finalize phase (see Program.Finalize) are
+// delivered to Update directly and don't pass through fn.
+//
+// Returning nil drops the message; returning a different Msg than the one
+// received substitutes it. Because the filter sees quitMsg before the event
+// loop does, it's the place to intercept program termination, for example
+// to show a "quit without saving?" dialog instead of exiting immediately.
+//
+// fn runs on the event-loop goroutine, so it must not block or call back
+// into the Program synchronously.
+func WithFilter(fn func(Model, Msg) Msg) ProgramOption {
+	return func(p *Program) {
+		p.filter = fn
+	}
+}
+
+// WithFinalizer registers fn to run once quitting begins, after the alt
+// screen has been exited but before Run returns. fn receives the final
+// model and may return one last Cmd; its result is delivered to Update and
+// rendered inline, alongside anything queued via Program.Finalize. See
+// Program.Finalize for the motivating use case.
+func WithFinalizer(fn func(Model) (Model, Cmd)) ProgramOption {
+	return func(p *Program) {
+		p.finalizer = fn
+	}
+}
+
+// WithFinalizeTimeout overrides how long the finalize phase waits on
+// commands queued via Finalize, FinalizeMsg, or a WithFinalizer hook before
+// giving up and letting Run return. The default is five seconds.
+func WithFinalizeTimeout(timeout time.Duration) ProgramOption {
+	return func(p *Program) {
+		p.finalizeTimeout = timeout
+	}
+}
+
+// WithCommandShutdownTimeout makes shutdown wait up to timeout for
+// outstanding CmdContext commands (started via SendCtx, or via a Cmd
+// returned from Update) to return before continuing. Without this option,
+// shutdown doesn't wait at all and those goroutines are left to finish on
+// their own, which is the historical behavior.
+func WithCommandShutdownTimeout(timeout time.Duration) ProgramOption {
+	return func(p *Program) {
+		p.cmdShutdownTimeout = timeout
+	}
+}
+
+// WithLogger routes the standard log package's output, along with any
+// LogMsg values dispatched through the event loop and anything written via
+// Program.Log/Logf, to w for the life of the program instead of to the
+// controlling TTY, where it would corrupt the rendered frame. Writes are
+// buffered on a background goroutine so a burst of log lines can't block
+// on a slow w.
+//
+// A nil w is treated the same as omitting this option: logging stays
+// unconfigured and Log/Logf remain no-ops, rather than panicking the
+// background writer goroutine on its first write.
+//
+// LogToFile is a convenient way to get a w that points at a file.
+func WithLogger(w io.Writer) ProgramOption {
+	return func(p *Program) {
+		if w == nil {
+			return
+		}
+		p.loggerWriter = newAsyncWriter(w)
+		p.logger = log.New(p.loggerWriter, "", log.LstdFlags)
+	}
+}
@@ -0,0 +1,111 @@
+package tea
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestSendCtxForgetsCancelOnCompletion checks that a SendCtx command that
+// returns on its own (the common case) is pruned from p.cancels, rather than
+// sitting there forever under its token.
+func TestSendCtxForgetsCancelOnCompletion(t *testing.T) {
+	p := NewProgram(fakeModel{})
+
+	const token = "token"
+	release := make(chan struct{})
+	p.SendCtx(token, func(ctx context.Context) Msg {
+		<-release
+		return nil
+	})
+
+	p.cancelsMu.Lock()
+	if n := len(p.cancels[token]); n != 1 {
+		p.cancelsMu.Unlock()
+		t.Fatalf("cancels[%q] has %d entries before completion, want 1", token, n)
+	}
+	p.cancelsMu.Unlock()
+
+	close(release)
+
+	// Drain the Msg the command sends back on completion, and wait for its
+	// goroutine to finish, so forgetCancel has had a chance to run.
+	select {
+	case <-p.msgs:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for SendCtx's result")
+	}
+	p.cmdWG.Wait()
+
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+	if _, ok := p.cancels[token]; ok {
+		t.Fatalf("cancels[%q] still present after command completed", token)
+	}
+}
+
+// TestCancelMsgCancelsMatchingToken mirrors the CancelMsg case in eventLoop:
+// calling every cancel func registered under a token should cancel the
+// context passed to the matching SendCtx command, and leave other tokens
+// untouched.
+func TestCancelMsgCancelsMatchingToken(t *testing.T) {
+	p := NewProgram(fakeModel{})
+
+	const (
+		target = "target"
+		other  = "other"
+	)
+
+	cancelled := make(chan struct{})
+	p.SendCtx(target, func(ctx context.Context) Msg {
+		<-ctx.Done()
+		close(cancelled)
+		return nil
+	})
+
+	block := make(chan struct{})
+	p.SendCtx(other, func(ctx context.Context) Msg {
+		<-block
+		return nil
+	})
+
+	// Wait until both commands have registered their cancel funcs.
+	deadline := time.After(time.Second)
+	for {
+		p.cancelsMu.Lock()
+		ready := len(p.cancels[target]) == 1 && len(p.cancels[other]) == 1
+		p.cancelsMu.Unlock()
+		if ready {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for SendCtx registrations")
+		default:
+		}
+	}
+
+	// Replicate eventLoop's CancelMsg handling for target only.
+	p.cancelsMu.Lock()
+	for _, entry := range p.cancels[target] {
+		entry.cancel()
+	}
+	delete(p.cancels, target)
+	p.cancelsMu.Unlock()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("target command's context was never cancelled")
+	}
+
+	p.cancelsMu.Lock()
+	if _, ok := p.cancels[other]; !ok {
+		t.Fatal("other token's cancel entry was removed along with target's")
+	}
+	p.cancelsMu.Unlock()
+
+	close(block)
+	<-p.msgs // target's result
+	<-p.msgs // other's result
+}
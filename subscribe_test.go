@@ -0,0 +1,91 @@
+package tea
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeModel is the minimal Model used to drive Program in these tests
+// without a real terminal.
+type fakeModel struct{}
+
+func (fakeModel) Init() Cmd                { return nil }
+func (fakeModel) Update(Msg) (Model, Cmd)  { return fakeModel{}, nil }
+func (fakeModel) View() string             { return "" }
+
+
+// TestSubscribeForwardsAndCancels checks that messages sent on the source
+// channel reach p.msgs, and that the cancel func returned by Subscribe stops
+// forwarding without leaving its handler goroutine running.
+func TestSubscribeForwardsAndCancels(t *testing.T) {
+	p := NewProgram(fakeModel{})
+
+	source := make(chan Msg)
+	cancel := p.Subscribe(source)
+
+	source <- "hello"
+	select {
+	case msg := <-p.msgs:
+		if msg != "hello" {
+			t.Fatalf("got %v, want %q", msg, "hello")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for forwarded message")
+	}
+
+	cancel()
+	cancel() // must be safe to call more than once
+
+	done := make(chan struct{})
+	go func() {
+		p.waitHandlers()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitHandlers didn't return after cancel")
+	}
+}
+
+// TestSubscribeDrainsOnContextDone exercises the shutdown path: cancelling
+// p.ctx (as Run does on the way out) should make Subscribe's forwarding
+// goroutine exit even though its source never closes or sends again.
+func TestSubscribeDrainsOnContextDone(t *testing.T) {
+	p := NewProgram(fakeModel{})
+	p.Subscribe(make(chan Msg))
+
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.waitHandlers()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitHandlers didn't return after p.ctx was cancelled")
+	}
+}
+
+// TestSubscribeRacesWithWaitHandlers registers a subscription concurrently
+// with a call to waitHandlers, the shape of the race a consumer calling the
+// public Subscribe from one goroutine while Run tears down on another would
+// hit. Run with -race; it previously flagged handlers.shutdown reading
+// p.handlers without the lock addHandler writes under.
+func TestSubscribeRacesWithWaitHandlers(t *testing.T) {
+	p := NewProgram(fakeModel{})
+	p.cancel() // so the subscription's goroutine exits promptly either way
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Subscribe(make(chan Msg))
+	}()
+
+	p.waitHandlers()
+	wg.Wait()
+}
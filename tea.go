@@ -14,11 +14,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/containerd/console"
 	isatty "github.com/mattn/go-isatty"
@@ -30,6 +32,11 @@ import (
 // ErrProgramKilled is returned by [Program.Run] when the program got killed.
 var ErrProgramKilled = errors.New("program was killed")
 
+// defaultFinalizeTimeout is how long the finalize phase will wait for
+// commands queued via Finalize or returned by a WithFinalizer hook, unless
+// overridden with WithFinalizeTimeout.
+const defaultFinalizeTimeout = 5 * time.Second
+
 // Msg contain data from the result of a IO operation. Msgs trigger the update
 // function and, henceforth, the UI.
 type Msg interface{}
@@ -58,6 +65,26 @@ type Model interface {
 // update function.
 type Cmd func() Msg
 
+// CmdContext is like Cmd, but receives the context under which it's
+// running. A command started via Program.SendCtx, or the program's own
+// event loop, gets a context that's cancelled when the program shuts down
+// and, if the command was started with a token, when a matching CancelMsg
+// is sent. Long-running commands (HTTP calls, database queries) should
+// select on ctx.Done() so they return promptly instead of leaking their
+// goroutine until the underlying I/O completes on its own.
+type CmdContext func(context.Context) Msg
+
+// asCmdContext adapts a plain Cmd to the CmdContext signature so both can
+// be run through the same execution path. The adapted command still can't
+// be interrupted mid-flight — it just ignores the context — which is why
+// commands that need to observe cancellation should be written as
+// CmdContext and started with SendCtx instead.
+func asCmdContext(cmd Cmd) CmdContext {
+	return func(context.Context) Msg {
+		return cmd()
+	}
+}
+
 type handlers []chan struct{}
 
 // Options to customize the program during its initialization. These are
@@ -115,6 +142,72 @@ type Program struct {
 	altScreenWasActive bool
 	ignoreSignals      bool
 
+	// handlers is the list of goroutine lifetimes that Run waits on during
+	// shutdown. It's a Program field, rather than a local to Run, so that
+	// subscriptions registered via Subscribe or WithSubscriber can add
+	// themselves to it regardless of when they're started.
+	handlers   handlers
+	handlersMu sync.Mutex
+
+	// subscribers are external message sources supplied via WithSubscriber.
+	// They're started once Run has a live context to pass them.
+	subscribers []func(context.Context) <-chan Msg
+
+	// filter, if set via WithFilter, is given first look at every message
+	// read off p.msgs before the event loop's internal switch or Update.
+	// Returning nil drops the message.
+	filter func(Model, Msg) Msg
+
+	// finalizer, if set via WithFinalizer, is invoked with the final model
+	// once quitting begins, so it can return one last Cmd to run during the
+	// finalize phase.
+	finalizer func(Model) (Model, Cmd)
+
+	// finalizeTimeout bounds how long the finalize phase waits on commands
+	// queued via Finalize or returned by finalizer. Configured with
+	// WithFinalizeTimeout; defaultFinalizeTimeout is used if unset.
+	finalizeTimeout time.Duration
+
+	// finalizeCmds are commands queued via Finalize, to be run alongside
+	// whatever finalizer returns once quitting begins.
+	finalizeMu   sync.Mutex
+	finalizeCmds []Cmd
+
+	// cmdWG tracks outstanding CmdContext goroutines started by
+	// handleCommands or SendCtx. shutdown optionally waits on it, up to
+	// cmdShutdownTimeout, instead of leaking them until the underlying Cmd
+	// returns.
+	cmdWG sync.WaitGroup
+
+	// cmdShutdownTimeout bounds how long shutdown waits on cmdWG.
+	// Configured with WithCommandShutdownTimeout; zero (the default) means
+	// don't wait, preserving the historical leak-the-goroutine behavior.
+	cmdShutdownTimeout time.Duration
+
+	// cancels holds the cancel funcs for outstanding SendCtx commands,
+	// keyed by the token they were started with, so a CancelMsg can abort
+	// the matching cohort. Each entry carries an id, assigned from
+	// cancelSeq, so it can be pruned again once its command returns on its
+	// own instead of sitting in the map for the life of the program.
+	cancelsMu sync.Mutex
+	cancels   map[string][]cancelEntry
+	cancelSeq int
+
+	// logger receives log package output and LogMsg values once WithLogger
+	// is configured. It's nil by default, which makes Log and Logf no-ops
+	// rather than writing to the controlling TTY, which raw mode would
+	// corrupt. loggerWriter is the buffered sink underneath it, kept
+	// around so Run can drain it during shutdown.
+	logger       *log.Logger
+	loggerWriter *asyncWriter
+
+	// prevLogOutput, prevLogFlags and prevLogPrefix save the standard log
+	// package's configuration so it can be restored in shutdown after Run
+	// redirects it to p.logger's writer.
+	prevLogOutput io.Writer
+	prevLogFlags  int
+	prevLogPrefix string
+
 	// Stores the original reference to stdin for cases where input is not a
 	// TTY on windows and we've automatically opened CONIN$ to receive input.
 	// When the program exits this will be restored.
@@ -134,12 +227,43 @@ func Quit() Msg {
 // send a quitMsg with Quit.
 type quitMsg struct{}
 
+// FinalizeMsg, returned from a Cmd, queues cmds to run during the finalize
+// phase and then quits the program, exactly as Program.Finalize does. It's
+// the Cmd-based equivalent of calling Finalize directly, for code that only
+// has access to a Cmd and not the Program itself.
+type FinalizeMsg struct {
+	Cmds []Cmd
+}
+
+// CancelMsg asks the program to cancel the context passed to any
+// outstanding CmdContext commands that were started via Program.SendCtx
+// with a matching Token. Send it the same way you'd send Quit, e.g. from a
+// Cmd or a call to Program.Send.
+type CancelMsg struct {
+	Token string
+}
+
+// cancelEntry pairs a SendCtx command's cancel func with the id it was
+// registered under, so Program.cancels can find and remove it again once
+// the command it belongs to returns on its own.
+type cancelEntry struct {
+	id     int
+	cancel context.CancelFunc
+}
+
+// LogMsg carries a line of debug output to the logger configured via
+// WithLogger, instead of being delivered to Update. Dispatch it like any
+// other Msg, e.g. from a Cmd, so debug output produced during Update stays
+// interleaved with everything else the event loop is doing.
+type LogMsg string
+
 // NewProgram creates a new Program.
 func NewProgram(model Model, opts ...ProgramOption) *Program {
 	p := &Program{
 		initialModel: model,
 		input:        os.Stdin,
 		msgs:         make(chan Msg),
+		cancels:      make(map[string][]cancelEntry),
 	}
 
 	// Initialize context and teardown channel.
@@ -244,12 +368,19 @@ func (p *Program) handleCommands(cmds chan Cmd) chan struct{} {
 					continue
 				}
 
-				// Don't wait on these goroutines, otherwise the shutdown
-				// latency would get too large as a Cmd can run for some time
-				// (e.g. tick commands that sleep for half a second). It's not
-				// possible to cancel them so we'll have to leak the goroutine
+				// By default we don't wait on these goroutines, otherwise
+				// shutdown latency would get too large as a Cmd can run for
+				// some time (e.g. tick commands that sleep for half a
+				// second). They're tracked in cmdWG, though, so a caller
+				// that configures WithCommandShutdownTimeout can have
+				// shutdown wait for them instead of leaking the goroutine
 				// until Cmd returns.
-				go p.Send(cmd())
+				ctxCmd := asCmdContext(cmd)
+				p.cmdWG.Add(1)
+				go func() {
+					defer p.cmdWG.Done()
+					p.Send(ctxCmd(p.ctx))
+				}()
 			}
 		}
 	}()
@@ -269,10 +400,46 @@ func (p *Program) eventLoop(model Model, cmds chan Cmd) (Model, error) {
 			return model, err
 
 		case msg := <-p.msgs:
+			// Give the user-supplied filter, if any, first look at every
+			// message read off the queue, including the internal ones
+			// handled below (quitMsg, batchMsg, sequenceMsg, execMsg, and
+			// so on). The filter runs on this goroutine, so it can veto or
+			// rewrite quitMsg itself to intercept program termination (for
+			// a "quit and lose changes?" prompt, say); returning nil drops
+			// the message before either the internal switch or Update sees
+			// it.
+			if p.filter != nil {
+				msg = p.filter(model, msg)
+			}
+			if msg == nil {
+				continue
+			}
+
 			// Handle special internal messages.
 			switch msg := msg.(type) {
 			case quitMsg:
-				return model, nil
+				return p.finalize(model), nil
+
+			case FinalizeMsg:
+				p.finalizeMu.Lock()
+				p.finalizeCmds = append(p.finalizeCmds, msg.Cmds...)
+				p.finalizeMu.Unlock()
+				return p.finalize(model), nil
+
+			case CancelMsg:
+				p.cancelsMu.Lock()
+				for _, entry := range p.cancels[msg.Token] {
+					entry.cancel()
+				}
+				delete(p.cancels, msg.Token)
+				p.cancelsMu.Unlock()
+				continue
+
+			case LogMsg:
+				if p.logger != nil {
+					p.logger.Print(string(msg))
+				}
+				continue
 
 			case clearScreenMsg:
 				p.renderer.clearScreen()
@@ -316,6 +483,34 @@ func (p *Program) eventLoop(model Model, cmds chan Cmd) (Model, error) {
 						p.Send(cmd())
 					}
 				}()
+
+			case SubscribeMsg:
+				source := msg.Source
+				if msg.Transform != nil {
+					transform := msg.Transform
+					transformed := make(chan Msg)
+					go func() {
+						defer close(transformed)
+						for {
+							select {
+							case <-p.ctx.Done():
+								return
+							case m, ok := <-source:
+								if !ok {
+									return
+								}
+								select {
+								case <-p.ctx.Done():
+									return
+								case transformed <- transform(m):
+								}
+							}
+						}
+					}()
+					source = transformed
+				}
+				p.Subscribe(source)
+				continue
 			}
 
 			// Process internal messages for the renderer.
@@ -331,16 +526,108 @@ func (p *Program) eventLoop(model Model, cmds chan Cmd) (Model, error) {
 	}
 }
 
+// finalize runs once quitting begins, before eventLoop returns. It exits
+// the alt screen (leaving the inline renderer live), invokes the
+// WithFinalizer hook with the final model, and runs that hook's Cmd
+// together with any commands queued via Finalize or FinalizeMsg, bounded
+// by finalizeTimeout. Every resulting message is delivered to Update and
+// rendered inline before Run returns; a Cmd returned from one of those
+// Update calls is run the same way and can itself queue further messages,
+// same as eventLoop's own handling of Update's (Model, Cmd) contract.
+//
+// This runs its own goroutine/select loop instead of going through
+// handleCommands, since by the time these commands run eventLoop has
+// stopped reading p.msgs, so results that handleCommands sends there would
+// never reach Update.
+func (p *Program) finalize(model Model) Model {
+	if p.renderer != nil {
+		p.renderer.exitAltScreen()
+	}
+
+	p.finalizeMu.Lock()
+	finalizeCmds := p.finalizeCmds
+	p.finalizeCmds = nil
+	p.finalizeMu.Unlock()
+
+	if p.finalizer != nil {
+		var cmd Cmd
+		model, cmd = p.finalizer(model)
+		if cmd != nil {
+			finalizeCmds = append(finalizeCmds, cmd)
+		}
+	}
+
+	if len(finalizeCmds) == 0 {
+		return model
+	}
+
+	timeout := p.finalizeTimeout
+	if timeout <= 0 {
+		timeout = defaultFinalizeTimeout
+	}
+	// Deriving from p.ctx, rather than context.Background(), means a
+	// concurrent Kill cancels this wait immediately instead of leaving it
+	// to run for the full timeout.
+	ctx, cancel := context.WithTimeout(p.ctx, timeout)
+	defer cancel()
+
+	results := make(chan Msg)
+	var pending int
+	run := func(cmd Cmd) {
+		pending++
+		go func() {
+			select {
+			case results <- cmd():
+			case <-ctx.Done():
+			}
+		}()
+	}
+	for _, cmd := range finalizeCmds {
+		if cmd != nil {
+			run(cmd)
+		}
+	}
+
+	for pending > 0 {
+		select {
+		case msg := <-results:
+			pending--
+
+			var cmd Cmd
+			model, cmd = model.Update(msg)
+			if cmd != nil {
+				run(cmd)
+			}
+			p.renderer.write(model.View())
+
+		case <-ctx.Done():
+			return model
+		}
+	}
+
+	return model
+}
+
 // Run initializes the program and runs its event loops, blocking until it gets
 // terminated by either [Program.Quit], [Program.Kill], or its signal handler.
 // Returns the final model.
 func (p *Program) Run() (Model, error) {
-	handlers := handlers{}
 	cmds := make(chan Cmd)
 	p.errs = make(chan error)
 
 	defer p.cancel()
 
+	// If a logger was configured with WithLogger, redirect the standard
+	// log package's output to it for the duration of Run so that a stray
+	// log.Print from somewhere in the program doesn't corrupt the
+	// rendered frame; shutdown restores the previous configuration.
+	if p.logger != nil {
+		p.prevLogOutput = log.Writer()
+		p.prevLogFlags = log.Flags()
+		p.prevLogPrefix = log.Prefix()
+		log.SetOutput(p.logger.Writer())
+	}
+
 	switch {
 	case p.startupOptions.has(withInputTTY):
 		// Open a new TTY, by request
@@ -376,7 +663,7 @@ func (p *Program) Run() (Model, error) {
 
 	// Handle signals.
 	if !p.startupOptions.has(withoutSignalHandler) {
-		handlers.add(p.handleSignals())
+		p.addHandler(p.handleSignals())
 	}
 
 	// Recover from panics.
@@ -416,7 +703,7 @@ func (p *Program) Run() (Model, error) {
 	model := p.initialModel
 	if initCmd := model.Init(); initCmd != nil {
 		ch := make(chan struct{})
-		handlers.add(ch)
+		p.addHandler(ch)
 
 		go func() {
 			defer close(ch)
@@ -443,10 +730,15 @@ func (p *Program) Run() (Model, error) {
 	}
 
 	// Handle resize events.
-	handlers.add(p.handleResize())
+	p.addHandler(p.handleResize())
 
 	// Process commands.
-	handlers.add(p.handleCommands(cmds))
+	p.addHandler(p.handleCommands(cmds))
+
+	// Start any subscribers registered via WithSubscriber.
+	for _, subscriber := range p.subscribers {
+		p.Subscribe(subscriber(p.ctx))
+	}
 
 	// Run event loop, handle updates and draw.
 	model, err := p.eventLoop(model, cmds)
@@ -467,7 +759,7 @@ func (p *Program) Run() (Model, error) {
 	}
 
 	// Wait for all handlers to finish.
-	handlers.shutdown()
+	p.waitHandlers()
 
 	// Restore terminal state.
 	p.shutdown(killed)
@@ -508,6 +800,130 @@ func (p *Program) Send(msg Msg) {
 	}
 }
 
+// SendCtx runs cmd and delivers its result with Send, the context-aware
+// counterpart to calling Send from a goroutine you started yourself. cmd
+// receives a context that's cancelled when the program shuts down, or
+// earlier if a CancelMsg carrying the same token is sent; pass an empty
+// token if you don't need targeted cancellation.
+//
+// This gives long-running commands (HTTP requests, database queries) a way
+// to observe program termination and actually abort, rather than leaking a
+// goroutine until the underlying I/O completes on its own.
+func (p *Program) SendCtx(token string, cmd CmdContext) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	id := p.trackCancel(token, cancel)
+
+	p.cmdWG.Add(1)
+	go func() {
+		defer p.cmdWG.Done()
+		defer p.forgetCancel(token, id)
+		defer cancel()
+		p.Send(cmd(ctx))
+	}()
+}
+
+// trackCancel registers cancel under token, so a CancelMsg carrying that
+// token can reach it, and returns an id that forgetCancel can later use to
+// remove it again.
+func (p *Program) trackCancel(token string, cancel context.CancelFunc) int {
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+
+	p.cancelSeq++
+	id := p.cancelSeq
+	p.cancels[token] = append(p.cancels[token], cancelEntry{id: id, cancel: cancel})
+	return id
+}
+
+// forgetCancel removes the cancel entry id from token's cohort once the
+// SendCtx command it belongs to has returned on its own, so a long-running
+// program reusing the same token (including the empty one) doesn't
+// accumulate cancel funcs for commands that already finished.
+func (p *Program) forgetCancel(token string, id int) {
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+
+	entries := p.cancels[token]
+	for i, entry := range entries {
+		if entry.id == id {
+			p.cancels[token] = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(p.cancels[token]) == 0 {
+		delete(p.cancels, token)
+	}
+}
+
+// Subscribe registers an external source of messages with the program. Every
+// message received on source is forwarded into the event loop and delivered
+// to Update, with the same backpressure and cancellation semantics as Send:
+// delivery blocks until the event loop is ready, and is abandoned once the
+// program has shut down.
+//
+// This is the hook for bridging an external event bus (a domain layer, a
+// pub/sub client, a plain Go channel fed by another goroutine) into the TEA
+// update function without hand-rolling a forwarding goroutine per source.
+//
+// The returned cancel function stops forwarding from source; it does not
+// close source, and it's safe to call more than once. Subscriptions are also
+// torn down automatically when the program exits, so calling cancel is only
+// necessary to stop a subscription early.
+func (p *Program) Subscribe(source <-chan Msg) (cancel func()) {
+	done := make(chan struct{})
+	p.addHandler(done)
+
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() { close(stop) })
+	}
+
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-stop:
+				return
+			case msg, ok := <-source:
+				if !ok {
+					return
+				}
+
+				select {
+				case <-p.ctx.Done():
+					return
+				case <-stop:
+					return
+				case p.msgs <- msg:
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// SubscribeMsg is returned by a Cmd to ask the program to open a long-lived
+// external message source. Every message received on Source is passed
+// through Transform, if set, and delivered to Update, exactly as if
+// Program.Subscribe had been called directly. Unlike Program.Subscribe,
+// which runs immediately, a SubscribeMsg is routed through handleCommands
+// like any other command result.
+type SubscribeMsg struct {
+	// Source is the channel of messages to subscribe to.
+	Source <-chan Msg
+
+	// Transform, if non-nil, maps each message read from Source before it's
+	// delivered to Update. This is what lets an adapter bridge a
+	// domain-specific event type (e.g. a partybus or NATS payload) into a
+	// Msg the rest of the program understands.
+	Transform func(Msg) Msg
+}
+
 // Quit is a convenience function for quitting Bubble Tea programs. Use it
 // when you need to shut down a Bubble Tea program from the outside.
 //
@@ -519,6 +935,25 @@ func (p *Program) Quit() {
 	p.Send(Quit())
 }
 
+// Finalize queues cmds to run during the finalize phase — after quit has
+// been requested but before Run returns — and then quits the program.
+// Unlike a Cmd returned from Update, messages produced by these commands
+// are rendered inline, after the alt screen has been exited, provided they
+// arrive within the finalize timeout (see WithFinalizeTimeout); results
+// that arrive later are dropped. This makes it the place to flush summary
+// output that must
+// survive past the end of the TUI (for example a SBOM or scan tool
+// rendering a final report after the interactive view closes).
+//
+// This replaces the fragile pattern of calling Println from a goroutine
+// immediately before calling Quit, which races the program's own shutdown.
+func (p *Program) Finalize(cmds ...Cmd) {
+	p.finalizeMu.Lock()
+	p.finalizeCmds = append(p.finalizeCmds, cmds...)
+	p.finalizeMu.Unlock()
+	p.Quit()
+}
+
 // Kill stops the program immediately and restores the former terminal state.
 // The final render that you would normally see when quitting will be skipped.
 // [program.Run] returns a [ErrProgramKilled] error.
@@ -537,12 +972,41 @@ func (p *Program) shutdown(kill bool) {
 		}
 	}
 
+	if p.cmdShutdownTimeout > 0 {
+		p.waitForCommands(p.cmdShutdownTimeout)
+	}
+
+	if p.logger != nil {
+		log.SetOutput(p.prevLogOutput)
+		log.SetFlags(p.prevLogFlags)
+		log.SetPrefix(p.prevLogPrefix)
+	}
+	if p.loggerWriter != nil {
+		p.loggerWriter.Close()
+	}
+
 	_ = p.restoreTerminalState()
 	if p.restoreOutput != nil {
 		_ = p.restoreOutput()
 	}
 }
 
+// waitForCommands blocks until every outstanding CmdContext command started
+// by handleCommands or SendCtx has returned, or until timeout elapses,
+// whichever comes first.
+func (p *Program) waitForCommands(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		p.cmdWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
 // ReleaseTerminal restores the original terminal state and cancels the input
 // reader. You can return control to the Program with RestoreTerminal.
 func (p *Program) ReleaseTerminal() error {
@@ -601,12 +1065,42 @@ func (p *Program) Printf(template string, args ...interface{}) {
 	}
 }
 
+// Log writes to the logger configured with WithLogger. It's a no-op if no
+// logger is configured. Unlike Println, this never touches the controlling
+// TTY, so it's safe to call for debug output while the program is running
+// in raw mode or the alt screen is active.
+func (p *Program) Log(args ...interface{}) {
+	if p.logger == nil {
+		return
+	}
+	p.logger.Print(args...)
+}
+
+// Logf is like Log, but takes a format template the way fmt.Printf and
+// log.Printf do.
+func (p *Program) Logf(template string, args ...interface{}) {
+	if p.logger == nil {
+		return
+	}
+	p.logger.Printf(template, args...)
+}
+
 // Adds a handler to the list of handlers. We wait for all handlers to terminate
 // gracefully on shutdown.
 func (h *handlers) add(ch chan struct{}) {
 	*h = append(*h, ch)
 }
 
+// addHandler registers ch with the program's handler list so that Run waits
+// for it to close before returning. Unlike appending to a local handlers
+// value, this is safe to call concurrently, which subscriptions need since
+// they may be registered from outside the event-loop goroutine.
+func (p *Program) addHandler(ch chan struct{}) {
+	p.handlersMu.Lock()
+	p.handlers.add(ch)
+	p.handlersMu.Unlock()
+}
+
 // Shutdown waits for all handlers to terminate.
 func (h handlers) shutdown() {
 	var wg sync.WaitGroup
@@ -619,3 +1113,16 @@ func (h handlers) shutdown() {
 	}
 	wg.Wait()
 }
+
+// waitHandlers waits for all handlers registered so far to terminate. It
+// takes a snapshot of p.handlers under p.handlersMu rather than calling
+// shutdown directly on the field, since addHandler — and therefore
+// Subscribe, running on another goroutine — can still be appending to it
+// concurrently with Run tearing down.
+func (p *Program) waitHandlers() {
+	p.handlersMu.Lock()
+	hs := p.handlers
+	p.handlersMu.Unlock()
+
+	hs.shutdown()
+}